@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Codec decodes a whole input into the generic string/float64/[]interface{}/
+// map[string]interface{} shape loremipsumizeAny walks, and encodes it back.
+type Codec interface {
+	Decode(io.Reader) (interface{}, error)
+	Encode(io.Writer, interface{}) error
+}
+
+var codecsByExt = map[string]Codec{
+	".json":   jsonCodec{},
+	".yml":    yamlCodec{},
+	".yaml":   yamlCodec{},
+	".toml":   tomlCodec{},
+	".xml":    xmlCodec{},
+	".ndjson": ndjsonCodec{},
+	".csv":    csvCodec{},
+}
+
+func codecExt(inputFile string) string {
+	if format != "" {
+		return "." + strings.ToLower(strings.TrimPrefix(format, "."))
+	}
+	return strings.ToLower(path.Ext(inputFile))
+}
+
+func codecFor(ext string) Codec {
+	if c, ok := codecsByExt[ext]; ok {
+		return c
+	}
+	return jsonCodec{}
+}
+
+// normalizeValue rewrites decoder output into the map[string]interface{} /
+// []interface{} shape loremipsumizeAny walks, converting yaml's
+// map[interface{}]interface{} along the way. Leaf scalars (string, float64,
+// int, int64, uint64, bool, time.Time) are left as the decoder produced them,
+// so e.g. a TOML integer round-trips as an integer rather than a float.
+func normalizeValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			m[fmt.Sprintf("%v", k)] = normalizeValue(vv)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			m[k] = normalizeValue(vv)
+		}
+		return m
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, vv := range t {
+			out[i] = normalizeValue(vv)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(r io.Reader) (interface{}, error) {
+	var v interface{}
+	err := json.NewDecoder(r).Decode(&v)
+	return v, err
+}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Decode(r io.Reader) (interface{}, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return normalizeValue(v), nil
+}
+
+func (yamlCodec) Encode(w io.Writer, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+type tomlCodec struct{}
+
+func (tomlCodec) Decode(r io.Reader) (interface{}, error) {
+	var v interface{}
+	if _, err := toml.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeValue(v), nil
+}
+
+func (tomlCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("toml codec: expected map[string]interface{}, got %T", v)
+	}
+	return toml.NewEncoder(w).Encode(m)
+}
+
+// xmlCodec represents an XML document as a map[string]interface{}: attributes
+// become "@name" keys, character data becomes "#text", the root tag name is
+// stashed under "#root", repeated child tags become []interface{}, and
+// "#order" records child tag names in document order so sibling interleaving
+// round-trips instead of being alphabetized.
+type xmlCodec struct{}
+
+func (xmlCodec) Decode(r io.Reader) (interface{}, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			m, err := xmlDecodeElement(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			m["#root"] = start.Name.Local
+			return m, nil
+		}
+	}
+}
+
+func xmlDecodeElement(dec *xml.Decoder, start xml.StartElement) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		m["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	var order []string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := xmlDecodeElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			order = append(order, t.Name.Local)
+			switch existing := m[t.Name.Local].(type) {
+			case nil:
+				m[t.Name.Local] = child
+			case []interface{}:
+				m[t.Name.Local] = append(existing, child)
+			default:
+				m[t.Name.Local] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if s := strings.TrimSpace(text.String()); s != "" {
+				m["#text"] = s
+			}
+			if len(order) > 0 {
+				m["#order"] = order
+			}
+			return m, nil
+		}
+	}
+}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("xml codec: expected map[string]interface{}, got %T", v)
+	}
+	root, _ := m["#root"].(string)
+	if root == "" {
+		root = "root"
+	}
+	enc := xml.NewEncoder(w)
+	if err := xmlEncodeElement(enc, root, m); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func xmlEncodeElement(enc *xml.Encoder, name string, v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if v != nil {
+			if err := enc.EncodeToken(xml.CharData([]byte(fmt.Sprintf("%v", v)))); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(xml.EndElement{Name: start.Name})
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	for k, vv := range m {
+		if strings.HasPrefix(k, "@") {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: strings.TrimPrefix(k, "@")}, Value: fmt.Sprintf("%v", vv)})
+		}
+	}
+	sort.Slice(start.Attr, func(i, j int) bool { return start.Attr[i].Name.Local < start.Attr[j].Name.Local })
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	order, _ := m["#order"].([]string)
+	if order == nil {
+		// no recorded order (e.g. a map built by hand rather than decoded
+		// from XML): fall back to a stable, if arbitrary, key order.
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			if strings.HasPrefix(k, "@") || k == "#root" || k == "#order" || k == "#text" {
+				continue
+			}
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			order = append(order, k)
+		}
+	}
+
+	consumed := map[string]int{}
+	for _, k := range order {
+		child := m[k]
+		if items, ok := child.([]interface{}); ok {
+			i := consumed[k]
+			consumed[k] = i + 1
+			if i >= len(items) {
+				continue
+			}
+			child = items[i]
+		}
+		if err := xmlEncodeElement(enc, k, child); err != nil {
+			return err
+		}
+	}
+
+	if text, ok := m["#text"]; ok {
+		if err := enc.EncodeToken(xml.CharData([]byte(fmt.Sprintf("%v", text)))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+// csvCodec treats the header row as map keys, so a CSV of rows decodes to
+// []interface{} of map[string]interface{} and ignorefile suffixes like
+// "./email" match every row the same way they match a JSON object's field.
+type csvCodec struct{}
+
+func (csvCodec) Decode(r io.Reader) (interface{}, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []interface{}{}, nil
+	}
+
+	header := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := map[string]interface{}{}
+		for i, h := range header {
+			if i < len(record) {
+				row[h] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (csvCodec) Encode(w io.Writer, v interface{}) error {
+	rows, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("csv codec: expected []interface{}, got %T", v)
+	}
+
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	first, ok := rows[0].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("csv codec: expected row to be map[string]interface{}, got %T", rows[0])
+	}
+	header := make([]string, 0, len(first))
+	for k := range first {
+		header = append(header, k)
+	}
+	sort.Strings(header)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		row, ok := r.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("csv codec: expected row to be map[string]interface{}, got %T", r)
+		}
+		record := make([]string, len(header))
+		for i, h := range header {
+			record[i] = fmt.Sprintf("%v", row[h])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	return cw.Error()
+}
+
+// ndjsonCodec decodes/encodes a whole NDJSON document as []interface{}, one
+// element per line. loremipsumize itself bypasses this in favour of
+// loremipsumizeNDJSON, which reuses the streaming tokenizer to process one
+// line at a time instead of buffering the whole file.
+type ndjsonCodec struct{}
+
+func (ndjsonCodec) Decode(r io.Reader) (interface{}, error) {
+	var rows []interface{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, err
+		}
+		rows = append(rows, v)
+	}
+	return rows, scanner.Err()
+}
+
+func (ndjsonCodec) Encode(w io.Writer, v interface{}) error {
+	rows, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("ndjson codec: expected []interface{}, got %T", v)
+	}
+	enc := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loremipsumizeNDJSON reuses streamValue to process one line at a time, so
+// multi-GB NDJSON exports don't need to fit in memory.
+func loremipsumizeNDJSON(r io.Reader, dict map[string]string, outputFile string) error {
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		if err := streamValue(dec, w, dict, []string{"."}); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestYAMLCodecRoundTrip(t *testing.T) {
+	in := []byte("name: Jane\nage: 30\n")
+	v, err := yamlCodec{}.Decode(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected map[string]interface{}, got %T", v)
+	}
+	if m["name"] != "Jane" {
+		t.Fatalf("name = %#v, want Jane", m["name"])
+	}
+
+	var buf bytes.Buffer
+	if err := (yamlCodec{}).Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	v2, err := yamlCodec{}.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2 := v2.(map[string]interface{})
+	if m2["name"] != "Jane" || m2["age"] != 30 {
+		t.Fatalf("round trip mismatch: %#v", m2)
+	}
+}
+
+func TestTOMLCodecRoundTripPreservesIntType(t *testing.T) {
+	in := []byte("port = 8080\nname = \"svc\"\n")
+	v, err := tomlCodec{}.Decode(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if _, ok := m["port"].(int64); !ok {
+		t.Fatalf("port decoded as %T, want int64", m["port"])
+	}
+
+	var buf bytes.Buffer
+	if err := (tomlCodec{}).Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("8080.0")) {
+		t.Fatalf("toml int round-tripped as a float: %s", buf.String())
+	}
+}
+
+func TestXMLCodecRoundTripPreservesSiblingOrder(t *testing.T) {
+	in := []byte(`<a><x>1</x><y>2</y><x>3</x></a>`)
+	v, err := (xmlCodec{}).Decode(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (xmlCodec{}).Encode(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `<a><x>1</x><y>2</y><x>3</x></a>`; got != want {
+		t.Fatalf("xml round trip = %q, want %q", got, want)
+	}
+}
+
+func TestCSVCodecRoundTrip(t *testing.T) {
+	in := []byte("name,age\nJane,30\nJohn,40\n")
+	v, err := (csvCodec{}).Decode(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, ok := v.([]interface{})
+	if !ok || len(rows) != 2 {
+		t.Fatalf("decoded %#v", v)
+	}
+
+	var buf bytes.Buffer
+	if err := (csvCodec{}).Encode(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	v2, err := (csvCodec{}).Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := v2.([]interface{})[0].(map[string]interface{})
+	if row["name"] != "Jane" || row["age"] != "30" {
+		t.Fatalf("round trip mismatch: %#v", row)
+	}
+}
+
+func TestNDJSONCodecRoundTrip(t *testing.T) {
+	in := []byte("{\"a\":1}\n{\"b\":2}\n")
+	v, err := (ndjsonCodec{}).Decode(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := v.([]interface{})
+	if len(rows) != 2 {
+		t.Fatalf("decoded %d rows, want 2", len(rows))
+	}
+
+	var buf bytes.Buffer
+	if err := (ndjsonCodec{}).Encode(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "{\"a\":1}\n{\"b\":2}\n"; got != want {
+		t.Fatalf("ndjson round trip = %q, want %q", got, want)
+	}
+}
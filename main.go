@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"log"
 	"math/rand"
@@ -14,6 +15,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -23,16 +25,42 @@ var outputPath string
 var dictionaryFile string
 var ignoreFile string
 var ignoreContent []string
+var rulesFile string
+var rules []Rule
+var seed int64
+var strictMode bool
+var rng *rand.Rand
+var streamThreshold int64
+var watchMode bool
+var watchPatterns string
+var watchDelay time.Duration
+var format string
 
 func main() {
 	flag.StringVar(&inputFiles, "files", "", "space separated list of input files")
 	flag.StringVar(&outputPath, "outdir", os.TempDir(), "path to write loremipsumized files")
-	flag.Parse() // we want to use `outputPath`
-
-	flag.StringVar(&dictionaryFile, "dictfile", path.Join(outputPath, "lorem.yml"), "(optional) dictionary of translations")
+	flag.StringVar(&dictionaryFile, "dictfile", "", "(optional) dictionary of translations (default <outdir>/lorem.yml)")
 	flag.StringVar(&ignoreFile, "ignorefile", ".lorem.ignore", "(optional) dictionary of translations")
+	flag.StringVar(&rulesFile, "rulesfile", ".lorem.rules.yml", "(optional) JSON-path rules for format-preserving anonymization")
+	flag.Int64Var(&seed, "seed", 0, "seed for math/rand; 0 defaults to a hash of -dictfile")
+	flag.BoolVar(&strictMode, "strict", false, "refuse to invent a new mapping for tokens missing from -dictfile")
+	flag.Int64Var(&streamThreshold, "stream-threshold", 64*1024*1024, "use the streaming JSON path for input files at least this many bytes")
+	flag.BoolVar(&watchMode, "watch", false, "keep running, re-loremipsumizing -files and -patterns matches as they change")
+	flag.StringVar(&watchPatterns, "patterns", "", "space separated glob patterns (e.g. **/*.json) watched in addition to -files")
+	flag.DurationVar(&watchDelay, "delay", 100*time.Millisecond, "debounce delay before reprocessing a changed file in -watch mode")
+	flag.StringVar(&format, "format", "", "override codec selection (json, yaml, toml, xml, ndjson, csv) instead of inferring from file extension")
 	flag.Parse()
 
+	if dictionaryFile == "" {
+		dictionaryFile = path.Join(outputPath, "lorem.yml")
+	}
+
+	if seed == 0 {
+		seed = hashSeed(dictionaryFile)
+	}
+	rng = rand.New(rand.NewSource(seed))
+	log.Printf("seed = %d", seed)
+
 	data, err := ioutil.ReadFile(ignoreFile)
 	if err == nil {
 		b := bufio.NewReader(bytes.NewReader(data))
@@ -46,18 +74,33 @@ func main() {
 	}
 	log.Printf("ignoreContent = %#v", ignoreContent)
 
+	data, err = ioutil.ReadFile(rulesFile)
+	if err == nil {
+		if err := yaml.Unmarshal(data, &rules); err != nil {
+			log.Println(err.Error())
+		}
+	}
+	log.Printf("rules = %#v", rules)
+
 	dict, fn := dictionary(dictionaryFile)
 	defer fn()
 	if dict == nil {
 		return
 	}
 
-	for _, s := range extractInputfiles(inputFiles) {
+	targets := extractInputfiles(inputFiles)
+	for _, s := range targets {
 		if err := loremipsumize(s, dict, path.Join(outputPath, path.Base(s))); err != nil {
 			log.Println(err.Error())
 			break
 		}
 	}
+
+	if watchMode {
+		if err := runWatch(targets, watchPatterns, dict, outputPath, dictionaryFile); err != nil {
+			log.Println(err.Error())
+		}
+	}
 }
 
 func extractInputfiles(str string) []string {
@@ -100,8 +143,52 @@ func loremipsumize(inputFile string, dict map[string]string, outputFile string)
 	}
 	defer f.Close()
 
+	ext := codecExt(inputFile)
+
+	if ext == ".ndjson" {
+		log.Printf("[loremipsumize] streaming %s as ndjson", inputFile)
+		return loremipsumizeNDJSON(bufio.NewReader(f), dict, outputFile)
+	}
+
+	if ext != ".json" && ext != "" {
+		codec := codecFor(ext)
+		v, err := codec.Decode(f)
+		if err != nil {
+			return err
+		}
+
+		v2, err := loremipsumizeAny(v, dict, []string{"."})
+		if err != nil {
+			return err
+		}
+
+		out, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		err = codec.Encode(out, v2)
+		log.Printf("[loremipsumize] wrote %s %#v", outputFile, err)
+		return err
+	}
+
+	br := bufio.NewReader(f)
+	large := false
+	if stat, err := f.Stat(); err == nil {
+		large = stat.Size() >= streamThreshold
+	}
+	isArray, err := peekIsArray(br)
+	if err != nil {
+		return err
+	}
+
+	if large || isArray {
+		log.Printf("[loremipsumize] streaming %s (large=%v isArray=%v)", inputFile, large, isArray)
+		return loremipsumizeStream(br, dict, outputFile)
+	}
+
 	v := map[string]interface{}{}
-	json.NewDecoder(f).Decode(&v)
+	json.NewDecoder(br).Decode(&v)
 
 	v2, err := loremipsumizeMap(v, dict, []string{"."})
 	if err != nil {
@@ -117,6 +204,26 @@ func loremipsumize(inputFile string, dict map[string]string, outputFile string)
 	return err
 }
 
+// peekIsArray looks past leading whitespace to see whether the next JSON
+// token is an array delimiter, without consuming anything from r.
+func peekIsArray(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\n', '\r':
+			r.ReadByte()
+			continue
+		case '[':
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+}
+
 func loremipsumizeFloat64(v float64, dict map[string]string, nesting []string) (interface{}, error) {
 	s := strings.TrimSuffix(fmt.Sprintf("%f", v), ".000000")
 	s2, err := loremipsumizeString(s, dict, nesting)
@@ -134,23 +241,44 @@ var characterSetRegexp = map[string]*regexp.Regexp{
 
 func loremipsumizeString(v string, dict map[string]string, nesting []string) (interface{}, error) {
 	var data = []byte(v)
+	var strictErr error
 	for characterSets, re := range characterSetRegexp {
 		data = re.ReplaceAllFunc(data, func(old []byte) []byte {
 			if isIgnored(fmt.Sprintf("%#v", string(old))) {
 				return old
 			}
 
+			if existing, ok := dict[string(old)]; ok {
+				return []byte(existing)
+			}
+
+			if strictMode {
+				strictErr = fmt.Errorf("strict mode: no dictionary entry for %q", string(old))
+				return old
+			}
+
 			new := make([]byte, len(old))
 			for i := len(old) - 1; i >= 0; i-- {
-				new[i] = byte(characterSets[rand.Intn(len(characterSets))])
+				new[i] = byte(characterSets[rng.Intn(len(characterSets))])
 			}
 			dict[string(old)] = string(new)
 			return new
 		})
+		if strictErr != nil {
+			return string(data), strictErr
+		}
 	}
 	return string(data), nil
 }
 
+// hashSeed derives a stable seed from a file path so reruns against the same
+// -dictfile reproduce the same substitutions unless -seed is set explicitly.
+func hashSeed(s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int64(h.Sum64())
+}
+
 func loremipsumizeArray(input []interface{}, dict map[string]string, nesting []string) (interface{}, error) {
 	output := []interface{}{}
 	for _, v := range input {
@@ -163,18 +291,52 @@ func loremipsumizeArray(input []interface{}, dict map[string]string, nesting []s
 	return output, nil
 }
 
+// loremipsumizeStringValue dispatches a leaf string to its -rulesfile rule
+// when nesting matches one, falling back to character-class scrambling
+// otherwise. Both the in-memory walk (loremipsumizeAny) and the streaming
+// walk (streamValue) go through this so top-level arrays and NDJSON rows
+// honor rules the same way plain objects do.
+func loremipsumizeStringValue(s string, dict map[string]string, nesting []string) (interface{}, error) {
+	if rule := ruleFor(nesting); rule != nil {
+		return applyRule(*rule, s, dict)
+	}
+	return loremipsumizeString(s, dict, nesting)
+}
+
 func loremipsumizeAny(v interface{}, dict map[string]string, nesting []string) (interface{}, error) {
-	switch v.(type) {
+	switch t := v.(type) {
 	case nil:
 		return v, nil
+	case bool:
+		return v, nil
+	case time.Time:
+		return v, nil
 	case float64:
-		return loremipsumizeFloat64(v.(float64), dict, nesting)
+		return loremipsumizeFloat64(t, dict, nesting)
+	case int:
+		f, err := loremipsumizeFloat64(float64(t), dict, nesting)
+		if err != nil {
+			return t, err
+		}
+		return int(f.(float64)), nil
+	case int64:
+		f, err := loremipsumizeFloat64(float64(t), dict, nesting)
+		if err != nil {
+			return t, err
+		}
+		return int64(f.(float64)), nil
+	case uint64:
+		f, err := loremipsumizeFloat64(float64(t), dict, nesting)
+		if err != nil {
+			return t, err
+		}
+		return uint64(f.(float64)), nil
 	case string:
-		return loremipsumizeString(v.(string), dict, nesting)
+		return loremipsumizeStringValue(t, dict, nesting)
 	case []interface{}:
-		return loremipsumizeArray(v.([]interface{}), dict, nesting)
+		return loremipsumizeArray(t, dict, nesting)
 	case map[string]interface{}:
-		return loremipsumizeMap(v.(map[string]interface{}), dict, nesting)
+		return loremipsumizeMap(t, dict, nesting)
 	default:
 		log.Fatalf("unknown type %#v", v)
 		return nil, nil
@@ -184,6 +346,12 @@ func loremipsumizeAny(v interface{}, dict map[string]string, nesting []string) (
 func loremipsumizeMap(input map[string]interface{}, dict map[string]string, nesting []string) (map[string]interface{}, error) {
 	output := map[string]interface{}{}
 	for k, v := range input {
+		if k == "#root" || k == "#order" {
+			// the XML root tag name and child ordering (see xmlCodec in codec.go)
+			// are structure, not data.
+			output[k] = v
+			continue
+		}
 		currNesting := append(nesting, k)
 		if isIgnored(strings.Join(currNesting, "/")) {
 			output[k] = v
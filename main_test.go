@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMainFlagsTakeEffect builds the real binary and execs it, rather than
+// calling main() in-process, so it exercises the exact flag.Parse() flow a
+// user hits on the command line (this is the gap that let -seed silently
+// fall back to its default across a two-stage flag.Parse()).
+func TestMainFlagsTakeEffect(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "loremipsumizer")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %s\n%s", err, out)
+	}
+
+	input := filepath.Join(dir, "in.json")
+	if err := os.WriteFile(input, []byte(`{"a":"hello"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(bin, "-seed", "5", "-files", input, "-outdir", dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("run: %s\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "seed = 5") {
+		t.Fatalf("output did not reflect -seed flag:\n%s", out)
+	}
+}
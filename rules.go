@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Rule binds a JSON-path pattern (e.g. "./user/email" or "./**/phone") to a
+// typed generator (e.g. "email", "phone", "iso8601:7", "regex:^[A-Z]{3}$"),
+// loaded from -rulesfile.
+type Rule struct {
+	Pattern string `yaml:"pattern"`
+	Type    string `yaml:"type"`
+}
+
+func ruleFor(nesting []string) *Rule {
+	actualSegs := strings.Join(nesting, "/")
+	for i := range rules {
+		if matchRulePattern(strings.Split(rules[i].Pattern, "/"), strings.Split(actualSegs, "/")) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// matchRulePattern treats "**" as a wildcard for zero or more segments and
+// matches every other segment with path.Match; also backs -watch's -patterns.
+func matchRulePattern(patternSegs, actualSegs []string) bool {
+	if len(patternSegs) == 0 {
+		return len(actualSegs) == 0
+	}
+	if patternSegs[0] == "**" {
+		if matchRulePattern(patternSegs[1:], actualSegs) {
+			return true
+		}
+		return len(actualSegs) > 0 && matchRulePattern(patternSegs, actualSegs[1:])
+	}
+	if len(actualSegs) == 0 {
+		return false
+	}
+	if ok, err := path.Match(patternSegs[0], actualSegs[0]); err != nil || !ok {
+		return false
+	}
+	return matchRulePattern(patternSegs[1:], actualSegs[1:])
+}
+
+func applyRule(rule Rule, v string, dict map[string]string) (interface{}, error) {
+	if existing, ok := dict[v]; ok {
+		return existing, nil
+	}
+	if strictMode {
+		return v, fmt.Errorf("strict mode: no dictionary entry for %q", v)
+	}
+
+	kind, arg := rule.Type, ""
+	if i := strings.Index(rule.Type, ":"); i >= 0 {
+		kind, arg = rule.Type[:i], rule.Type[i+1:]
+	}
+
+	var out string
+	switch kind {
+	case "email":
+		out = generateEmail(v)
+	case "phone":
+		out = generatePhone(v)
+	case "uuid":
+		out = generateUUIDv4()
+	case "iso8601":
+		days := 30
+		if n, err := strconv.Atoi(arg); err == nil {
+			days = n
+		}
+		out = generateISO8601(v, days)
+	case "luhn":
+		out = generateLuhn(v)
+	case "regex":
+		generated, err := generateRegexMatch(arg)
+		if err != nil {
+			return v, err
+		}
+		out = generated
+	default:
+		return v, fmt.Errorf("unknown rule type %q for pattern %q", rule.Type, rule.Pattern)
+	}
+
+	dict[v] = out
+	return out, nil
+}
+
+func scramble(s string) string {
+	data := []byte(s)
+	for characterSets, re := range characterSetRegexp {
+		data = re.ReplaceAllFunc(data, func(old []byte) []byte {
+			new := make([]byte, len(old))
+			for i := range old {
+				new[i] = byte(characterSets[rng.Intn(len(characterSets))])
+			}
+			return new
+		})
+	}
+	return string(data)
+}
+
+func generateEmail(v string) string {
+	at := strings.LastIndex(v, "@")
+	if at < 0 {
+		return scramble(v)
+	}
+	local, domain := v[:at], v[at+1:]
+	dot := strings.LastIndex(domain, ".")
+	if dot < 0 {
+		return scramble(local) + "@" + scramble(domain)
+	}
+	label, tld := domain[:dot], domain[dot:]
+	return scramble(local) + "@" + scramble(label) + tld
+}
+
+func generatePhone(v string) string {
+	runes := []rune(v)
+	for i, r := range runes {
+		if unicode.IsDigit(r) {
+			runes[i] = rune('0' + rng.Intn(10))
+		}
+	}
+	return string(runes)
+}
+
+func generateUUIDv4() string {
+	b := make([]byte, 16)
+	for i := range b {
+		b[i] = byte(rng.Intn(256))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+var iso8601Layouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func generateISO8601(v string, days int) string {
+	for _, layout := range iso8601Layouts {
+		t, err := time.Parse(layout, v)
+		if err != nil {
+			continue
+		}
+		jitter := time.Duration(rng.Intn(2*days+1)-days) * 24 * time.Hour
+		return t.Add(jitter).Format(layout)
+	}
+	return v
+}
+
+// luhnCheckDigit computes the check digit that makes digits, followed by
+// that digit, pass the Luhn algorithm.
+func luhnCheckDigit(digits []int) int {
+	sum := 0
+	for i, n := 0, len(digits); i < n; i++ {
+		d := digits[n-1-i]
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (sum * 9) % 10
+}
+
+func generateLuhn(v string) string {
+	runes := []rune(v)
+	var digitPositions []int
+	for i, r := range runes {
+		if unicode.IsDigit(r) {
+			digitPositions = append(digitPositions, i)
+		}
+	}
+	if len(digitPositions) == 0 {
+		return v
+	}
+
+	digits := make([]int, len(digitPositions)-1)
+	for i := range digits {
+		digits[i] = rng.Intn(10)
+	}
+	allDigits := append(digits, luhnCheckDigit(digits))
+
+	for i, pos := range digitPositions {
+		runes[pos] = rune('0' + allDigits[i])
+	}
+	return string(runes)
+}
+
+// generateRegexMatch builds a candidate by walking pattern's parsed syntax
+// tree (regexp/syntax) rather than sampling random alnum strings, which can
+// never satisfy literal punctuation (e.g. "^\d{3}-\d{2}-\d{4}$").
+func generateRegexMatch(pattern string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < 1000; attempt++ {
+		if candidate := genFromSyntax(parsed); re.MatchString(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("could not generate a value matching regex %q after 1000 attempts", pattern)
+}
+
+func genFromSyntax(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpCharClass:
+		lo, hi := charClassRange(re.Rune)
+		return string(lo + rune(rng.Intn(int(hi-lo+1))))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return string(rune(33 + rng.Intn(94))) // printable, non-space ASCII
+	case syntax.OpCapture:
+		return genFromSyntax(re.Sub[0])
+	case syntax.OpStar:
+		return genRepeat(re.Sub[0], 0, 3)
+	case syntax.OpPlus:
+		return genRepeat(re.Sub[0], 1, 4)
+	case syntax.OpQuest:
+		return genRepeat(re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		max := re.Max
+		if max < 0 || max > re.Min+3 {
+			max = re.Min + 3
+		}
+		return genRepeat(re.Sub[0], re.Min, max)
+	case syntax.OpConcat:
+		var sb strings.Builder
+		for _, sub := range re.Sub {
+			sb.WriteString(genFromSyntax(sub))
+		}
+		return sb.String()
+	case syntax.OpAlternate:
+		return genFromSyntax(re.Sub[rng.Intn(len(re.Sub))])
+	default: // anchors, word boundaries, empty match: contribute nothing
+		return ""
+	}
+}
+
+func genRepeat(sub *syntax.Regexp, min, max int) string {
+	n := min
+	if max > min {
+		n += rng.Intn(max - min + 1)
+	}
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString(genFromSyntax(sub))
+	}
+	return sb.String()
+}
+
+func charClassRange(ranges []rune) (rune, rune) {
+	pair := rng.Intn(len(ranges) / 2)
+	return ranges[pair*2], ranges[pair*2+1]
+}
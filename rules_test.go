@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLuhnCheckDigitProducesValidNumber(t *testing.T) {
+	rng = rand.New(rand.NewSource(1))
+	for _, v := range []string{"4111-1111-1111-1111", "1234567890"} {
+		out := generateLuhn(v)
+		if len(out) != len(v) {
+			t.Fatalf("generateLuhn(%q) = %q, length changed", v, out)
+		}
+		if !luhnValid(out) {
+			t.Fatalf("generateLuhn(%q) = %q, fails Luhn check", v, out)
+		}
+	}
+}
+
+func luhnValid(s string) bool {
+	var digits []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) == 0 {
+		return false
+	}
+	return luhnCheckDigit(digits[:len(digits)-1]) == digits[len(digits)-1]
+}
+
+func TestGenerateRegexMatchHandlesPunctuation(t *testing.T) {
+	rng = rand.New(rand.NewSource(1))
+	pattern := `^\d{3}-\d{2}-\d{4}$`
+	re := regexp.MustCompile(pattern)
+	for i := 0; i < 20; i++ {
+		out, err := generateRegexMatch(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !re.MatchString(out) {
+			t.Fatalf("generateRegexMatch(%q) = %q, does not match", pattern, out)
+		}
+	}
+}
+
+func TestGenerateISO8601JittersWithinRange(t *testing.T) {
+	rng = rand.New(rand.NewSource(1))
+	v := "2024-01-15T10:00:00Z"
+	days := 5
+	orig, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		out := generateISO8601(v, days)
+		got, err := time.Parse(time.RFC3339, out)
+		if err != nil {
+			t.Fatalf("generateISO8601 produced unparseable value %q: %s", out, err)
+		}
+		delta := got.Sub(orig)
+		if delta < -time.Duration(days)*24*time.Hour || delta > time.Duration(days)*24*time.Hour {
+			t.Fatalf("generateISO8601(%q) = %q, jitter %s outside ±%d days", v, out, delta, days)
+		}
+	}
+}
+
+func TestGenerateISO8601LeavesUnknownFormatUnchanged(t *testing.T) {
+	rng = rand.New(rand.NewSource(1))
+	v := "not-a-date"
+	if out := generateISO8601(v, 30); out != v {
+		t.Fatalf("generateISO8601(%q) = %q, want unchanged", v, out)
+	}
+}
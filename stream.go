@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// loremipsumizeStream walks r token by token with json.Decoder.Token(),
+// applying loremipsumizeString/loremipsumizeFloat64 to leaf values and
+// writing the result straight to outputFile, so multi-GB inputs never need
+// to be held in memory as a map[string]interface{}.
+func loremipsumizeStream(r io.Reader, dict map[string]string, outputFile string) error {
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(r)
+	w := bufio.NewWriter(f)
+
+	if err := streamValue(dec, w, dict, []string{"."}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func streamValue(dec *json.Decoder, w *bufio.Writer, dict map[string]string, nesting []string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			return streamObject(dec, w, dict, nesting)
+		case '[':
+			return streamArray(dec, w, dict, nesting)
+		}
+		return nil
+	case string:
+		v2, err := loremipsumizeStringValue(t, dict, nesting)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, v2)
+	case float64:
+		v2, err := loremipsumizeFloat64(t, dict, nesting)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, v2)
+	case nil:
+		_, err := w.WriteString("null")
+		return err
+	default:
+		return writeJSON(w, t)
+	}
+}
+
+func streamObject(dec *json.Decoder, w *bufio.Writer, dict map[string]string, nesting []string) error {
+	if err := w.WriteByte('{'); err != nil {
+		return err
+	}
+
+	for first := true; dec.More(); first = false {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := keyTok.(string)
+
+		if !first {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if err := writeJSON(w, key); err != nil {
+			return err
+		}
+		if err := w.WriteByte(':'); err != nil {
+			return err
+		}
+
+		currNesting := append(nesting, key)
+		if isIgnored(strings.Join(currNesting, "/")) {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			if _, err := w.Write(raw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := streamValue(dec, w, dict, currNesting); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil { // consume closing '}'
+		return err
+	}
+	return w.WriteByte('}')
+}
+
+func streamArray(dec *json.Decoder, w *bufio.Writer, dict map[string]string, nesting []string) error {
+	if err := w.WriteByte('['); err != nil {
+		return err
+	}
+
+	for first := true; dec.More(); first = false {
+		if !first {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if err := streamValue(dec, w, dict, nesting); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil { // consume closing ']'
+		return err
+	}
+	return w.WriteByte(']')
+}
+
+func writeJSON(w *bufio.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+)
+
+// runWatch keeps running, reusing dict across reruns of loremipsumize for
+// explicit (-files) targets and anything matching a -patterns glob, and only
+// rewrites dictionaryFile when dict actually changed.
+func runWatch(explicit []string, patternsStr string, dict map[string]string, outDir, dictionaryFile string) error {
+	patterns := strings.Fields(patternsStr)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	watchedDirs := map[string]bool{}
+	watchDir := func(dir string) {
+		if dir == "" {
+			dir = "."
+		}
+		if watchedDirs[dir] {
+			return
+		}
+		if err := watcher.Add(dir); err != nil {
+			log.Printf("[watch] cannot watch %s: %s", dir, err)
+			return
+		}
+		watchedDirs[dir] = true
+	}
+
+	for _, f := range explicit {
+		watchDir(path.Dir(f))
+	}
+	if len(patterns) > 0 {
+		filepath.Walk(".", func(p string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() {
+				watchDir(p)
+			}
+			return nil
+		})
+	}
+
+	lastDictBytes, _ := yaml.Marshal(dict)
+	writeDictIfChanged := func() {
+		data, err := yaml.Marshal(dict)
+		if err != nil || bytes.Equal(data, lastDictBytes) {
+			return
+		}
+		if err := ioutil.WriteFile(dictionaryFile, data, 0600); err != nil {
+			log.Println("[watch] write dict:", err.Error())
+			return
+		}
+		lastDictBytes = data
+		log.Println("[watch] wrote dictionary", dictionaryFile)
+	}
+
+	matches := func(file string) bool {
+		for _, f := range explicit {
+			if filepath.Clean(f) == filepath.Clean(file) {
+				return true
+			}
+		}
+		actualSegs := strings.Split(filepath.ToSlash(file), "/")
+		for _, p := range patterns {
+			if matchRulePattern(strings.Split(p, "/"), actualSegs) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// process touches dict and the package-level rng, so every call must run
+	// on the worker goroutine below rather than directly on a timer callback.
+	process := func(file string) {
+		if err := loremipsumize(file, dict, path.Join(outDir, path.Base(file))); err != nil {
+			log.Println("[watch]", err.Error())
+			return
+		}
+		writeDictIfChanged()
+	}
+
+	toProcess := make(chan string)
+	go func() {
+		for file := range toProcess {
+			process(file)
+		}
+	}()
+
+	log.Printf("[watch] watching %v and patterns %v (delay=%s)", explicit, patterns, watchDelay)
+
+	timers := map[string]*time.Timer{}
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				close(toProcess)
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 || !matches(event.Name) {
+				continue
+			}
+			file := event.Name
+			if t, exists := timers[file]; exists {
+				t.Stop()
+			}
+			timers[file] = time.AfterFunc(watchDelay, func() { toProcess <- file })
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				close(toProcess)
+				return nil
+			}
+			log.Println("[watch] error:", err.Error())
+		}
+	}
+}